@@ -0,0 +1,66 @@
+// Package auth provides a pluggable backend for storing and verifying
+// player credentials used by the SRP-6a login handshake in package
+// main's login.go and srp.go. A credential is a salt and an SRP
+// verifier (g^x mod N, never the password or x itself), so a backend
+// never holds anything from which a login proof can be derived without
+// also knowing the password.
+package auth
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrAuthBackendExists is returned by SetAuthBackend when a backend has
+// already been registered for the process.
+var ErrAuthBackendExists = errors.New("auth: backend already registered")
+
+// AuthBackend stores the salted verifier for each known player and the
+// last server SRP value issued to them, so it can be swapped between an
+// in-memory map (tests) and a SQLite-backed store (production) without
+// touching the login handshake itself.
+type AuthBackend interface {
+	// Exists reports whether name has a stored credential.
+	Exists(name string) bool
+
+	// Passwd returns the salt and verifier stored for name.
+	Passwd(name string) (salt, verifier []byte, err error)
+
+	// SetPasswd overwrites the salt and verifier stored for an
+	// existing name.
+	SetPasswd(name string, salt, verifier []byte) error
+
+	// AddUser creates a new name with the given salt and verifier.
+	AddUser(name string, salt, verifier []byte) error
+
+	// LastSrvSrp returns the server SRP value most recently issued to
+	// name during a login challenge, so a backend can audit or resume
+	// an in-flight handshake.
+	LastSrvSrp(name string) ([]byte, error)
+}
+
+var (
+	mu      sync.Mutex
+	backend AuthBackend
+)
+
+// SetAuthBackend registers the backend used by the package.  It may be
+// called only once per process; subsequent calls return
+// ErrAuthBackendExists.
+func SetAuthBackend(b AuthBackend) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if backend != nil {
+		return ErrAuthBackendExists
+	}
+	backend = b
+	return nil
+}
+
+// Backend returns the currently registered backend, or nil if none has
+// been set.
+func Backend() AuthBackend {
+	mu.Lock()
+	defer mu.Unlock()
+	return backend
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrUnknownUser is returned by Passwd, SetPasswd, and LastSrvSrp when
+// no credential is stored for the given name.
+var ErrUnknownUser = errors.New("auth: unknown user")
+
+// ErrUserExists is returned by AddUser when name is already registered.
+var ErrUserExists = errors.New("auth: user already exists")
+
+type memUser struct {
+	salt, verifier, lastSrvSrp []byte
+}
+
+// MemoryBackend is an in-memory AuthBackend, useful for tests and for
+// running the server without a persistent user store.
+type MemoryBackend struct {
+	mu    sync.Mutex
+	users map[string]*memUser
+}
+
+// NewMemoryBackend returns an empty in-memory backend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{users: map[string]*memUser{}}
+}
+
+func (m *MemoryBackend) Exists(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.users[name]
+	return ok
+}
+
+func (m *MemoryBackend) Passwd(name string) (salt, verifier []byte, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[name]
+	if !ok {
+		return nil, nil, ErrUnknownUser
+	}
+	return u.salt, u.verifier, nil
+}
+
+func (m *MemoryBackend) SetPasswd(name string, salt, verifier []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[name]
+	if !ok {
+		return ErrUnknownUser
+	}
+	u.salt, u.verifier = salt, verifier
+	return nil
+}
+
+func (m *MemoryBackend) AddUser(name string, salt, verifier []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[name]; ok {
+		return ErrUserExists
+	}
+	m.users[name] = &memUser{salt: salt, verifier: verifier}
+	return nil
+}
+
+func (m *MemoryBackend) LastSrvSrp(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[name]
+	if !ok {
+		return nil, ErrUnknownUser
+	}
+	return u.lastSrvSrp, nil
+}
+
+// RecordSrvSrp stores the server SRP value most recently issued to
+// name.  It is not part of AuthBackend: the login handshake uses it
+// through an optional interface so non-SRP backends can skip it.
+func (m *MemoryBackend) RecordSrvSrp(name string, b []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[name]
+	if !ok {
+		return ErrUnknownUser
+	}
+	u.lastSrvSrp = b
+	return nil
+}
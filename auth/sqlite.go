@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"database/sql"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteBackend is an AuthBackend backed by a SQLite database, for
+// running the server with credentials that survive a restart.
+type SQLiteBackend struct {
+	db *sql.DB
+}
+
+// NewSQLiteBackend opens (creating if necessary) the SQLite database at
+// path and ensures its schema exists.
+func NewSQLiteBackend(path string) (*SQLiteBackend, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	const schema = `
+	CREATE TABLE IF NOT EXISTS users (
+		name          TEXT PRIMARY KEY,
+		salt          BLOB NOT NULL,
+		verifier      BLOB NOT NULL,
+		last_srv_srp  BLOB
+	);`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteBackend{db: db}, nil
+}
+
+func (s *SQLiteBackend) Exists(name string) bool {
+	var n int
+	err := s.db.QueryRow(`SELECT 1 FROM users WHERE name = ?`, name).Scan(&n)
+	return err == nil
+}
+
+func (s *SQLiteBackend) Passwd(name string) (salt, verifier []byte, err error) {
+	err = s.db.QueryRow(`SELECT salt, verifier FROM users WHERE name = ?`, name).Scan(&salt, &verifier)
+	if err == sql.ErrNoRows {
+		return nil, nil, ErrUnknownUser
+	}
+	return salt, verifier, err
+}
+
+func (s *SQLiteBackend) SetPasswd(name string, salt, verifier []byte) error {
+	res, err := s.db.Exec(`UPDATE users SET salt = ?, verifier = ? WHERE name = ?`, salt, verifier, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUnknownUser
+	}
+	return nil
+}
+
+func (s *SQLiteBackend) AddUser(name string, salt, verifier []byte) error {
+	if s.Exists(name) {
+		return ErrUserExists
+	}
+	_, err := s.db.Exec(`INSERT INTO users (name, salt, verifier) VALUES (?, ?, ?)`, name, salt, verifier)
+	return err
+}
+
+func (s *SQLiteBackend) LastSrvSrp(name string) ([]byte, error) {
+	var b []byte
+	err := s.db.QueryRow(`SELECT last_srv_srp FROM users WHERE name = ?`, name).Scan(&b)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnknownUser
+	}
+	return b, err
+}
+
+// RecordSrvSrp stores the server SRP value most recently issued to
+// name.  It is not part of AuthBackend: the login handshake uses it
+// through an optional interface so non-SRP backends can skip it.
+func (s *SQLiteBackend) RecordSrvSrp(name string, b []byte) error {
+	res, err := s.db.Exec(`UPDATE users SET last_srv_srp = ? WHERE name = ?`, b, name)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrUnknownUser
+	}
+	return nil
+}
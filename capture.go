@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// captureHeader is the first line of a capture file.  It records
+// enough of the game's starting state that a replay produces the same
+// uids and tick numbers the original run did, provided the commands
+// captured are themselves deterministic.
+type captureHeader struct {
+	NextUid int   `json:"nextuid"`
+	Seed    int64 `json:"seed"`
+	Tick    int   `json:"tick"`
+}
+
+// captureRecord is one captured command: every line after the header.
+type captureRecord struct {
+	T   int64  `json:"t"`
+	Src string `json:"src"`
+	Cmd string `json:"cmd"`
+}
+
+// capture appends successful commands to a newline-delimited JSON
+// file, flushing after each write so a crash doesn't lose the tail.
+type capture struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+var activeCapture *capture
+
+// startCapture creates path and writes its header, then records to it,
+// until the process exits, every command callParse successfully runs
+// through the game's command center. The "help" shortcut and the
+// "login"/"loginVerify" handshake commands return from callParse
+// before that point and are not recorded.
+func startCapture(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	header := captureHeader{NextUid: defaultGame.UidCounter(), Seed: rngSeed, Tick: defaultGame.CurrentTick()}
+	if err := writeJSONLine(f, header); err != nil {
+		f.Close()
+		return err
+	}
+	activeCapture = &capture{file: f}
+	return nil
+}
+
+func (c *capture) record(src, cmd string) {
+	rec := captureRecord{T: time.Now().UnixNano(), Src: src, Cmd: cmd}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := writeJSONLine(c.file, rec); err != nil {
+		log.Println("capture: write failed:", err)
+	}
+}
+
+func writeJSONLine(f *os.File, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// captureRecordIfActive records cmd against the current capture file,
+// if one is open.  src identifies the caller: "stdin" or a websocket
+// client id.
+func captureRecordIfActive(session interface{}, cmd string) {
+	if activeCapture == nil {
+		return
+	}
+	activeCapture.record(srcLabel(session), cmd)
+}
+
+func srcLabel(session interface{}) string {
+	if session == stdinSession {
+		return "stdin"
+	}
+	return fmt.Sprint(session)
+}
+
+// rngSeed and rng seed the game's randomness so a -replay run can
+// reproduce it.  Nothing yet draws from rng, but future gameplay
+// randomness (e.g. monster behavior) should use it rather than the
+// global math/rand source so captures stay replayable.
+var (
+	rngSeed = time.Now().UnixNano()
+	rng     = rand.New(rand.NewSource(rngSeed))
+)
+
+// replayTickMillis picks the same tick interval NewGame would give its
+// Simulator for cfg, so runReplay can drive tick1 at the original
+// cadence.
+func replayTickMillis(cfg Config) int {
+	if cfg.Tick > 0 {
+		return cfg.Tick
+	}
+	return int(defaultBaseInterval / time.Millisecond)
+}
+
+// runReplay reads a capture file written by startCapture and re-runs
+// its commands against a freshly initialized game, preserving the
+// original inter-arrival delays (scaled by timescale) so the timing
+// of a bug can be reproduced along with the commands that caused it.
+//
+// The replayed game is created paused, since its own wall-clock
+// Simulator ticking independently of the capture would desynchronize
+// g.tick from the recorded commands. Instead, runReplay itself calls
+// tick1 once per elapsed tick interval between records (also scaled by
+// timescale), so Pace/Target movement that happened between captured
+// commands in the original run is reproduced here too.
+func runReplay(cfg Config, path string, timescale float64) error {
+	initGame(cfg, true)
+	tickMillis := replayTickMillis(cfg)
+	tickInterval := time.Duration(tickMillis) * time.Millisecond
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		return errors.New("replay: empty capture file")
+	}
+	var header captureHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("replay: reading header: %w", err)
+	}
+	defaultGame.SetUidCounter(header.NextUid)
+	rngSeed = header.Seed
+	rng = rand.New(rand.NewSource(rngSeed))
+	defaultGame.SetTick(header.Tick)
+
+	first := true
+	var lastT int64
+	for scanner.Scan() {
+		var rec captureRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return fmt.Errorf("replay: reading record: %w", err)
+		}
+		if !first {
+			gap := rec.T - lastT
+			ticks := gap / int64(tickInterval)
+			for i := int64(0); i < ticks; i++ {
+				if delay := time.Duration(float64(tickInterval) * timescale); delay > 0 {
+					time.Sleep(delay)
+				}
+				defaultGame.tick1(tickMillis)
+			}
+			if remainder := gap - ticks*int64(tickInterval); remainder > 0 {
+				if delay := time.Duration(float64(remainder) * timescale); delay > 0 {
+					time.Sleep(delay)
+				}
+			}
+		}
+		first, lastT = false, rec.T
+
+		result, err := defaultGame.center.CallWithFunctionString(rec.Cmd)
+		if err != nil {
+			log.Printf("replay: %s: %v", rec.Cmd, err)
+			continue
+		}
+		fmt.Println(result)
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCaptureFile(t *testing.T, header captureHeader, records []captureRecord) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture.jsonl")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := writeJSONLine(f, header); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := writeJSONLine(f, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+// TestRunReplayDrivesTicksBetweenRecords is the regression test for the
+// determinism gap chunk0-4's review comment described: a paused
+// replay game never ran tick1 on its own, so Pace/Target movement
+// that happened between two captured commands in the original run
+// couldn't be reproduced. runReplay must drive tick1 itself, once per
+// elapsed tick interval between records.
+func TestRunReplayDrivesTicksBetweenRecords(t *testing.T) {
+	ensureAuthBackend(t)
+	cfg := Config{Tick: 50, Width: 4, GridSize: 4, Paces: PaceConfig{PacePlayer, PaceMonster, PaceProjectile}}
+	const tickGapNanos = 170_000_000 // 170ms, i.e. 3 whole 50ms ticks
+	path := writeCaptureFile(t, captureHeader{NextUid: 1, Seed: 1, Tick: 0}, []captureRecord{
+		{T: 0, Src: "stdin", Cmd: "help"},
+		{T: tickGapNanos, Src: "stdin", Cmd: "help"},
+	})
+
+	if err := runReplay(cfg, path, 0); err != nil {
+		t.Fatalf("runReplay: %v", err)
+	}
+	if got, want := defaultGame.CurrentTick(), 3; got != want {
+		t.Fatalf("tick count after replay = %d, want %d (170ms elapsed over a 50ms tick interval)", got, want)
+	}
+}
+
+func TestReplayTickMillisFallsBackToDefault(t *testing.T) {
+	if got := replayTickMillis(Config{Tick: 0}); time.Duration(got)*time.Millisecond != defaultBaseInterval {
+		t.Fatalf("replayTickMillis(Tick: 0) = %dms, want the default interval", got)
+	}
+	if got := replayTickMillis(Config{Tick: 75}); got != 75 {
+		t.Fatalf("replayTickMillis(Tick: 75) = %d, want 75", got)
+	}
+}
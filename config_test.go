@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigMissingFileReturnsDefaults(t *testing.T) {
+	cfg, err := loadConfig("")
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg != defaultConfig() {
+		t.Fatalf("loadConfig(\"\") = %+v, want %+v", cfg, defaultConfig())
+	}
+
+	cfg, err = loadConfig(filepath.Join(t.TempDir(), "does-not-exist.json"))
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg != defaultConfig() {
+		t.Fatalf("loadConfig(missing file) = %+v, want %+v", cfg, defaultConfig())
+	}
+}
+
+func TestLoadConfigFillsInMissingFields(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"listen": "0.0.0.0:9000"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+	if cfg.Listen != "0.0.0.0:9000" {
+		t.Fatalf("cfg.Listen = %q, want %q", cfg.Listen, "0.0.0.0:9000")
+	}
+	want := defaultConfig()
+	if cfg.Tick != want.Tick || cfg.GridSize != want.GridSize || cfg.Paces != want.Paces {
+		t.Fatalf("loadConfig with a partial file should fall back to defaults for unset fields, got %+v", cfg)
+	}
+}
+
+func TestLoadConfigMalformedFileErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadConfig(path); err == nil {
+		t.Fatal("loadConfig should fail on a malformed config file")
+	}
+}
+
+func TestGridDimensionPrefersWidthOverGridSize(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{"width set", Config{Width: 10, GridSize: 20}, 10},
+		{"only gridSize set", Config{GridSize: 20}, 20},
+		{"neither set", Config{}, GridSize},
+	}
+	for _, c := range cases {
+		if got := gridDimension(c.cfg); got != c.want {
+			t.Errorf("%s: gridDimension(%+v) = %d, want %d", c.name, c.cfg, got, c.want)
+		}
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory available: %v", err)
+	}
+	got, err := expandHome("~/config.json")
+	if err != nil {
+		t.Fatalf("expandHome: %v", err)
+	}
+	if want := filepath.Join(home, "config.json"); got != want {
+		t.Fatalf("expandHome(~/config.json) = %q, want %q", got, want)
+	}
+
+	if got, err := expandHome("/already/absolute"); err != nil || got != "/already/absolute" {
+		t.Fatalf("expandHome should leave a non-~ path untouched, got %q, %v", got, err)
+	}
+}
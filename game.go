@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tilegame/gameserver/commander"
+	"github.com/tilegame/gameserver/lobby"
+	"github.com/tilegame/gameserver/wshandle"
+)
+
+// Game owns one gamegrid, its entities, and the command center that
+// dispatches text commands against them. Factoring these out of
+// package-level globals lets multiple games coexist in one process,
+// each with its own websocket room, uid sequence, tick counter, lobby
+// registry, and login state.
+type Game struct {
+	Name    string
+	Room    *wshandle.ClientRoom
+	Lobbies *lobby.Registry
+
+	mu             sync.Mutex
+	grid           [][]int
+	nextUidCounter int
+	tick           int
+
+	// authMu guards challenges and sessions, the per-Game login
+	// handshake state: a session key (the websocket room's opaque
+	// client id, or stdinSession) is only meaningful within the room
+	// it came from, so this state can't be shared across Games the
+	// way nextUidCounter briefly was before this field existed.
+	authMu     sync.Mutex
+	challenges map[interface{}]*loginChallenge
+	sessions   map[interface{}]authSession
+
+	// joinedMu guards joined, which tracks which sessions have already
+	// joined a lobby successfully (see handleLobbyJoin in
+	// lobby_session.go). Scoped per-Game for the same reason
+	// challenges and sessions are.
+	joinedMu sync.Mutex
+	joined   map[interface{}]bool
+
+	ents   *EntMap
+	paces  PaceConfig
+	center *commander.Center
+}
+
+// NewGame allocates a width x height Game paced by paces, starts its
+// websocket room, and wires up its command center. Unless paused is
+// true, it also starts its Simulator ticking every tickMillis
+// milliseconds. paused is true for the game runReplay creates: replay
+// drives state by replaying captured commands at their original (or
+// timescale-adjusted) delays, and a live wall-clock ticker running
+// alongside it would advance g.tick independently of that replay,
+// breaking chunk0-4's determinism guarantee. Use createGame to also
+// register it so it can be found by name.
+func NewGame(name string, width, height int, paces PaceConfig, tickMillis int, paused bool) *Game {
+	g := &Game{
+		Name:           name,
+		Room:           wshandle.NewClientRoom(),
+		Lobbies:        lobby.NewRegistry(),
+		grid:           newGameGrid(width, height),
+		nextUidCounter: 1,
+		challenges:     map[interface{}]*loginChallenge{},
+		sessions:       map[interface{}]authSession{},
+		joined:         map[interface{}]bool{},
+		ents:           NewEntMap(),
+		paces:          paces,
+	}
+	g.center = &commander.Center{FuncMap: gameFuncMap(g)}
+	go messageWatcher(g)
+	if !paused {
+		go g.runSimulator(time.Duration(tickMillis) * time.Millisecond)
+	}
+	return g
+}
+
+// PlaceEntity adds e to g and marks its starting grid square occupied,
+// under the same lock the Simulator uses to move entities around, so
+// a join arriving mid-tick can't race with a move or collision.
+func (g *Game) PlaceEntity(e Entity) {
+	g.ents.Update(func(data map[int]Entity) {
+		data[e.Uid] = e
+		if g.inBounds(e.Current) {
+			g.grid[e.Current.Y][e.Current.X] = e.Uid
+		}
+	})
+}
+
+// RemoveEntity deletes the Entity with the given uid and clears its
+// grid slot, under the same lock PlaceEntity and the Simulator use, so
+// a delete arriving mid-tick can't race with a move. Clearing the grid
+// slot here matters: tick1 indexes g.grid to find an occupant and then
+// looks it up in ents by uid, so a stale grid cell pointing at a uid
+// EntMap no longer has would make it synthesize a zero-value Entity
+// and run it through collision resolution. It reports whether uid was
+// present.
+func (g *Game) RemoveEntity(uid int) bool {
+	var removed bool
+	g.ents.Update(func(data map[int]Entity) {
+		e, ok := data[uid]
+		if !ok {
+			return
+		}
+		g.clearGridSlot(e.Current, uid)
+		delete(data, uid)
+		removed = true
+	})
+	return removed
+}
+
+// nextUid returns a fresh uid, unique within g.
+func (g *Game) nextUid() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.nextUidCounter++
+	return g.nextUidCounter
+}
+
+// UidCounter returns the most recently issued uid, e.g. for a capture
+// header.
+func (g *Game) UidCounter() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.nextUidCounter
+}
+
+// SetUidCounter overrides the uid counter, e.g. when seeding a replay
+// from a capture header.
+func (g *Game) SetUidCounter(n int) {
+	g.mu.Lock()
+	g.nextUidCounter = n
+	g.mu.Unlock()
+}
+
+// CurrentTick and SetTick expose g's tick counter the same way.
+func (g *Game) CurrentTick() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.tick
+}
+
+func (g *Game) SetTick(n int) {
+	g.mu.Lock()
+	g.tick = n
+	g.mu.Unlock()
+}
+
+// Grid renders g's grid as the command-stream "grid" function always
+// has: a '.' per tile, one row per line.
+func (g *Game) Grid() string {
+	s := ""
+	for _, row := range g.GridSnapshot() {
+		for range row {
+			s += "."
+		}
+		s += "\n"
+	}
+	return s
+}
+
+// GridSnapshot returns a copy of g's grid, taken under the same lock
+// PlaceEntity and the Simulator's tick1 use to mutate it (EntMap's
+// mutex, which also guards the grid even though it isn't itself an
+// Entity field), so a reader never observes a tick half-written.
+func (g *Game) GridSnapshot() [][]int {
+	var out [][]int
+	g.ents.Update(func(map[int]Entity) {
+		out = make([][]int, len(g.grid))
+		for i, row := range g.grid {
+			out[i] = append([]int(nil), row...)
+		}
+	})
+	return out
+}
+
+// gameFuncMap is the set of text commands dispatched through a Game's
+// commander.Center. "login" and "loginVerify" are handled directly by
+// callParse instead, since the login handshake they run needs the
+// caller's session. g may be nil: it is only used to build the
+// process-wide help text, never invoked.
+func gameFuncMap(g *Game) map[string]interface{} {
+	return map[string]interface{}{
+		"grid":     g.Grid,
+		"help":     Help,
+		"add":      Add,
+		"mult":     Mult,
+		"addi":     addi,
+		"nextTick": g.NextTick,
+	}
+}
+
+var (
+	gamesMu sync.RWMutex
+	games   = map[string]*Game{}
+)
+
+// createGame allocates a Game and registers it under name, failing if
+// that name is already taken.
+func createGame(name string, width, height int, paces PaceConfig, tickMillis int, paused bool) (*Game, error) {
+	gamesMu.Lock()
+	defer gamesMu.Unlock()
+	if _, ok := games[name]; ok {
+		return nil, fmt.Errorf("game %q already exists", name)
+	}
+	g := NewGame(name, width, height, paces, tickMillis, paused)
+	games[name] = g
+	return g, nil
+}
+
+// getGame looks up a registered Game by name.
+func getGame(name string) (*Game, bool) {
+	gamesMu.RLock()
+	defer gamesMu.RUnlock()
+	g, ok := games[name]
+	return g, ok
+}
+
+// listGames returns every registered Game, in no particular order.
+func listGames() []*Game {
+	gamesMu.RLock()
+	defer gamesMu.RUnlock()
+	out := make([]*Game, 0, len(games))
+	for _, g := range games {
+		out = append(out, g)
+	}
+	return out
+}
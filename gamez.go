@@ -2,15 +2,19 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 
+	"github.com/tilegame/gameserver/auth"
 	"github.com/tilegame/gameserver/commander"
-	"github.com/tilegame/gameserver/wshandle"
 )
 
 const helpMessage = `
@@ -87,16 +91,138 @@ const (
 )
 
 var (
-	uid               = 1
-	gamegrid          = [GridSize][GridSize]int{}
-	ents              = NewEntMap()
-	center            = &commander.Center{FuncMap: fMap}
 	actualHelpMessage = ""
+
+	// defaultGame is the Game used by runStdin, capture, and replay. It
+	// is also registered in game.go's registry under the name
+	// "default", so runServer serves it at /games/default/ws like any
+	// REST-created game.
+	defaultGame *Game
 )
 
-func nextuid() int {
-	uid++
-	return uid
+// PaceConfig holds the number of milliseconds it takes each kind of
+// entity to move 1 tile.  It is loaded from Config.Paces, falling back
+// to PacePlayer, PaceMonster, and PaceProjectile when absent.
+type PaceConfig struct {
+	Player     int `json:"player"`
+	Monster    int `json:"monster"`
+	Projectile int `json:"projectile"`
+}
+
+// Config holds the gameplay constants and server options that used to
+// be hard-coded.  It is loaded with loadConfig and falls back to the
+// compiled defaults wherever a field is missing from the file on disk.
+type Config struct {
+	Tick     int        `json:"tick"`
+	GridSize int        `json:"gridSize"`
+	Width    int        `json:"width"`
+	Listen   string     `json:"listen"`
+	Paces    PaceConfig `json:"paces"`
+	AuthDB   string     `json:"authDb"`
+}
+
+// defaultConfig returns the Config equivalent of the old compile-time
+// constants, used whenever -config is omitted or the file is missing.
+func defaultConfig() Config {
+	return Config{
+		Tick:     100,
+		GridSize: GridSize,
+		Width:    GridSize,
+		Listen:   "localhost:8080",
+		Paces:    PaceConfig{PacePlayer, PaceMonster, PaceProjectile},
+	}
+}
+
+// expandHome replaces a leading "~/" with the current user's home
+// directory, leaving any other path untouched.
+func expandHome(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, path[2:]), nil
+}
+
+// loadConfig reads the JSON config file at path, expanding a leading
+// "~/" to the user's home directory.  A missing file (or an empty
+// path) is not an error: loadConfig returns the compiled defaults.
+// Fields left out of the file keep their default value.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	expanded, err := expandHome(path)
+	if err != nil {
+		return cfg, err
+	}
+	data, err := os.ReadFile(expanded)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// gridDimension picks the square grid size a Config describes,
+// preferring Width and falling back to the older GridSize name.
+func gridDimension(cfg Config) int {
+	if cfg.Width > 0 {
+		return cfg.Width
+	}
+	if cfg.GridSize > 0 {
+		return cfg.GridSize
+	}
+	return GridSize
+}
+
+// newGameGrid allocates a width x height grid of empty tiles.
+func newGameGrid(width, height int) [][]int {
+	g := make([][]int, height)
+	for i := range g {
+		g[i] = make([]int, width)
+	}
+	return g
+}
+
+// newConfiguredAuthBackend picks the AuthBackend cfg asks for: a
+// SQLiteBackend at cfg.AuthDB if set, so credentials survive a
+// restart, or the volatile MemoryBackend otherwise.
+func newConfiguredAuthBackend(cfg Config) (auth.AuthBackend, error) {
+	if cfg.AuthDB == "" {
+		return auth.NewMemoryBackend(), nil
+	}
+	return auth.NewSQLiteBackend(cfg.AuthDB)
+}
+
+// initGame creates the "default" Game from cfg, the one runStdin,
+// capture, and replay operate on, and makes sure an auth
+// backend is configured. paused is passed straight through to
+// createGame: runReplay calls initGame with paused=true so its replayed
+// game isn't also ticking on its own.
+func initGame(cfg Config, paused bool) {
+	g, err := createGame("default", gridDimension(cfg), gridDimension(cfg), cfg.Paces, cfg.Tick, paused)
+	if err != nil {
+		g, _ = getGame("default")
+	}
+	defaultGame = g
+	if auth.Backend() == nil {
+		backend, err := newConfiguredAuthBackend(cfg)
+		if err != nil {
+			log.Fatal("auth: ", err)
+		}
+		if err := auth.SetAuthBackend(backend); err != nil && err != auth.ErrAuthBackendExists {
+			log.Println("auth: failed to set default backend:", err)
+		}
+	}
 }
 
 // Location refers to a square on the game grid.
@@ -117,13 +243,14 @@ type Entity struct {
 	Health  int `json:",omitempty"`
 }
 
-// NewPlayer returns a default player character.
-func NewPlayer(name string) *Entity {
+// NewPlayer returns a default player character, with a uid freshly
+// allocated from game and paced however game is configured.
+func NewPlayer(game *Game, name string) *Entity {
 	return &Entity{
 		Kind:   "player",
 		Name:   name,
-		Uid:    nextuid(),
-		Pace:   PacePlayer,
+		Uid:    game.nextUid(),
+		Pace:   game.paces.Player,
 		Health: 100,
 	}
 }
@@ -145,42 +272,34 @@ func (em *EntMap) Add(e Entity) {
 	em.mutex.Unlock()
 }
 
+// All returns every Entity currently tracked, in no particular order.
+func (em *EntMap) All() []Entity {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	out := make([]Entity, 0, len(em.data))
+	for _, e := range em.data {
+		out = append(out, e)
+	}
+	return out
+}
+
+// Update runs f with exclusive access to em's underlying map, for
+// callers (the Simulator) that need to read and mutate several
+// entries as one atomic step.
+func (em *EntMap) Update(f func(data map[int]Entity)) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	f(em.data)
+}
+
 // ______________________________________________________________________
 //			    Game Commands
 // ======================================================================
 
-var fMap = map[string]interface{}{
-	"grid":  Grid,
-	"login": Login,
-	"help":  Help,
-	"add":   Add,
-	"mult":  Mult,
-	"addi":  addi,
-}
-
 func Help() string {
 	return actualHelpMessage
 }
 
-func Grid() string {
-	s := ""
-	for range gamegrid {
-		for range gamegrid {
-			s += "."
-		}
-		s += "\n"
-	}
-	return s
-}
-
-func Login(name string) int {
-	return 0
-}
-
-func Logout(name string) {
-	return
-}
-
 func Add(a, b float64) float64 {
 	return a + b
 }
@@ -202,7 +321,8 @@ func addi(a, b int) int {
 
 var clientNotThere = "MessageWatcher: got a message from id(%v), but no client exists.\n"
 
-func messageWatcher(room *wshandle.ClientRoom) {
+func messageWatcher(game *Game) {
+	room := game.Room
 	for {
 		select {
 		case msg := <-room.Messages:
@@ -211,7 +331,13 @@ func messageWatcher(room *wshandle.ClientRoom) {
 				log.Printf(clientNotThere, msg.Id)
 				continue
 			}
-			result, err := callParse(string(msg.Data))
+			if handled, err := handleLobbyJoin(game, room, msg.Id, client, string(msg.Data)); handled {
+				if err != nil {
+					fmt.Fprintln(client, err)
+				}
+				continue
+			}
+			result, err := callParse(game, msg.Id, string(msg.Data))
 			if err != nil {
 				fmt.Fprintln(client, err)
 			} else {
@@ -221,25 +347,38 @@ func messageWatcher(room *wshandle.ClientRoom) {
 	}
 }
 
-func callParse(s string) (string, error) {
+func callParse(game *Game, session interface{}, s string) (string, error) {
 	if s == "help" {
 		return Help(), nil
 	}
-	result, err := center.CallWithFunctionString(s)
+	if name, aHex, ok := parseLoginCommand(s); ok {
+		return loginStep1(game, session, name, aHex)
+	}
+	if m1, ok := parseLoginVerifyCommand(s); ok {
+		id, err := loginStep2(game, session, m1)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprint(id), nil
+	}
+	if mutatingCommands[commandName(s)] && !isAuthenticated(game, session) {
+		return "", errors.New("not logged in")
+	}
+	result, err := game.center.CallWithFunctionString(s)
 	if err != nil {
 		return "", err
 	}
+	captureRecordIfActive(session, s)
 	return fmt.Sprint(result), nil
 }
 
-func runServer() {
-	room := wshandle.NewClientRoom()
-	go messageWatcher(room)
+func runServer(cfg Config) {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", handle)
-	mux.HandleFunc("/ws", room.Handle)
+	mux.HandleFunc("/games", gamesHandler)
+	mux.HandleFunc("/games/", gamesHandler)
 	s := &http.Server{
-		Addr:    "localhost:8080",
+		Addr:    cfg.Listen,
 		Handler: mux,
 	}
 	fmt.Println("listening and serving on", s.Addr)
@@ -251,14 +390,14 @@ func handle(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, page)
 }
 
-func runStdin() {
+func runStdin(cfg Config) {
 	s := bufio.NewScanner(os.Stdin)
 	for s.Scan() {
 		line := s.Text()
 		if line == "" {
 			continue
 		}
-		result, err := callParse(line)
+		result, err := callParse(defaultGame, stdinSession, line)
 		if err != nil {
 			fmt.Println(err)
 		} else {
@@ -272,24 +411,78 @@ func runStdin() {
 // ----------------------------------------------------------------------
 
 var (
-	serverFlag     = false
-	serverFlagHelp = "Run websocket server instead of command line."
+	serverFlag        = false
+	serverFlagHelp    = "Run websocket server instead of command line."
+	configFlag        = ""
+	configFlagHelp    = "Path to a JSON config file (defaults used if omitted)."
+	captureFlag       = ""
+	captureFlagHelp   = "Append every successful command to this file as newline-delimited JSON."
+	replayFlag        = ""
+	replayFlagHelp    = "Replay a file written by -capture instead of running normally."
+	timescaleFlag     = 1.0
+	timescaleFlagHelp = "Scale factor applied to a -replay's original inter-arrival delays."
+	adduserFlag       = ""
+	adduserFlagHelp   = "Register a credential as \"name:password\" with the auth backend, then continue normally."
+	authdbFlag        = ""
+	authdbFlagHelp    = "Path to a SQLite database for credentials that survive a restart (volatile in-memory storage if omitted). Overrides Config.AuthDB."
 )
 
 func init() {
 	flag.BoolVar(&serverFlag, "serve", false, serverFlagHelp)
+	flag.StringVar(&configFlag, "config", "", configFlagHelp)
+	flag.StringVar(&captureFlag, "capture", "", captureFlagHelp)
+	flag.StringVar(&replayFlag, "replay", "", replayFlagHelp)
+	flag.Float64Var(&timescaleFlag, "timescale", 1.0, timescaleFlagHelp)
+	flag.StringVar(&adduserFlag, "adduser", "", adduserFlagHelp)
+	flag.StringVar(&authdbFlag, "authdb", "", authdbFlagHelp)
 	flag.Usage = func() {
 		fmt.Fprint(os.Stderr, helpMessage, "Command Line Usage:\n\n")
 		flag.PrintDefaults()
 	}
-	actualHelpMessage = prefixGeneratedHelp + center.HelpMessage()
+	actualHelpMessage = prefixGeneratedHelp + (&commander.Center{FuncMap: gameFuncMap(nil)}).HelpMessage()
 }
 
+// startupConfig is the Config main() loaded, kept around so REST-created
+// games (which don't specify pace in their request body) use the same
+// paces the process started with.
+var startupConfig Config
+
 func main() {
 	flag.Parse()
+	cfg, err := loadConfig(configFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if authdbFlag != "" {
+		cfg.AuthDB = authdbFlag
+	}
+	startupConfig = cfg
+
+	if replayFlag != "" {
+		if err := runReplay(cfg, replayFlag, timescaleFlag); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	initGame(cfg, false)
+	if adduserFlag != "" {
+		parts := strings.SplitN(adduserFlag, ":", 2)
+		if len(parts) != 2 {
+			log.Fatal("-adduser must be in \"name:password\" form")
+		}
+		if err := registerUser(parts[0], parts[1]); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if captureFlag != "" {
+		if err := startCapture(captureFlag); err != nil {
+			log.Fatal(err)
+		}
+	}
 	if serverFlag {
-		runServer()
+		runServer(cfg)
 	} else {
-		runStdin()
+		runStdin(cfg)
 	}
 }
@@ -0,0 +1,126 @@
+// Package lobby groups connected players into passphrase-identified
+// rooms that survive a dropped connection, so a player can return to
+// the same game by reconnecting with the same lobby passphrase and
+// player name instead of being assigned a fresh one.
+package lobby
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Binding ties a player name to the uid of their Entity and the
+// connection id they were last seen on.  ConnID is opaque to this
+// package: the caller supplies it and an isAlive func to interpret it,
+// since only the caller (the websocket layer) knows whether a
+// connection is still open.
+type Binding struct {
+	Uid    int
+	ConnID interface{}
+}
+
+// Lobby is the set of players sharing one passphrase.
+type Lobby struct {
+	mu       sync.Mutex
+	bindings map[string]*Binding
+}
+
+// Registry scopes a set of Lobbies to one owner. Each owner (normally
+// one Game) gets its own Registry, so two owners that happen to reuse
+// the same passphrase don't hand out uids from each other's bindings.
+type Registry struct {
+	mu      sync.RWMutex
+	lobbies map[string]*Lobby
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{lobbies: map[string]*Lobby{}}
+}
+
+// Get returns the lobby for passphrase within r, creating it if this is
+// the first time it has been seen.
+func (r *Registry) Get(passphrase string) *Lobby {
+	r.mu.RLock()
+	l, ok := r.lobbies[passphrase]
+	r.mu.RUnlock()
+	if ok {
+		return l
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if l, ok := r.lobbies[passphrase]; ok {
+		return l
+	}
+	l = &Lobby{bindings: map[string]*Binding{}}
+	r.lobbies[passphrase] = l
+	return l
+}
+
+// Join binds connID to player inside the lobby.
+//
+// If player hasn't been seen before, newEntity is called to allocate
+// their Entity and a fresh Binding is recorded.  If player has been
+// seen but isAlive reports their last connection is gone, they are
+// reconnecting: the existing uid is rebound to connID.  If player has
+// been seen and isAlive reports their last connection is still live,
+// Join refuses: ok is false and the caller should drop the new
+// connection rather than disturb the existing one, so a flaky mobile
+// connection doesn't knock out a player's live session.
+func (l *Lobby) Join(player string, connID interface{}, isAlive func(interface{}) bool, newEntity func() int) (uid int, created, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, exists := l.bindings[player]
+	if exists {
+		if isAlive(b.ConnID) {
+			return 0, false, false
+		}
+		b.ConnID = connID
+		return b.Uid, false, true
+	}
+	uid = newEntity()
+	l.bindings[player] = &Binding{Uid: uid, ConnID: connID}
+	return uid, true, true
+}
+
+// PlayerCount returns the number of players ever bound to l, including
+// ones that are currently disconnected.
+func (l *Lobby) PlayerCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.bindings)
+}
+
+// List returns the passphrase of every lobby currently tracked in r,
+// sorted for stable output.
+func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.lobbies))
+	for p := range r.lobbies {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+type listEntry struct {
+	Passphrase string `json:"passphrase"`
+	Players    int    `json:"players"`
+}
+
+// HandleList serves a /games/{name}/lobbies-style endpoint: a JSON
+// array of every lobby in r and how many players have joined it.
+func (r *Registry) HandleList(w http.ResponseWriter, req *http.Request) {
+	r.mu.RLock()
+	out := make([]listEntry, 0, len(r.lobbies))
+	for p, l := range r.lobbies {
+		out = append(out, listEntry{Passphrase: p, Players: l.PlayerCount()})
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Passphrase < out[j].Passphrase })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
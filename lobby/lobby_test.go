@@ -0,0 +1,86 @@
+package lobby
+
+import "testing"
+
+func TestJoinAllocatesOnFirstSeen(t *testing.T) {
+	r := NewRegistry()
+	l := r.Get("passphrase")
+	calls := 0
+	newEntity := func() int { calls++; return 42 }
+	alwaysAlive := func(interface{}) bool { return true }
+
+	uid, created, ok := l.Join("alice", "conn-1", alwaysAlive, newEntity)
+	if !ok || !created || uid != 42 {
+		t.Fatalf("Join = %d, %v, %v, want 42, true, true", uid, created, ok)
+	}
+	if calls != 1 {
+		t.Fatalf("newEntity called %d times, want 1", calls)
+	}
+}
+
+func TestJoinRefusesASecondLiveConnection(t *testing.T) {
+	r := NewRegistry()
+	l := r.Get("passphrase")
+	newEntity := func() int { return 1 }
+	alwaysAlive := func(interface{}) bool { return true }
+
+	if _, _, ok := l.Join("alice", "conn-1", alwaysAlive, newEntity); !ok {
+		t.Fatal("first join should succeed")
+	}
+	if _, _, ok := l.Join("alice", "conn-2", alwaysAlive, newEntity); ok {
+		t.Fatal("a second join for the same name should be refused while the first connection is still alive")
+	}
+}
+
+// TestJoinAllowsReconnectAfterDisconnect is the regression test for the
+// lockout chunk0-3's review comment described: once the original
+// connection is no longer alive, the same name must be able to rejoin
+// (e.g. after a dropped connection reconnects) without being treated
+// as a fresh player.
+func TestJoinAllowsReconnectAfterDisconnect(t *testing.T) {
+	r := NewRegistry()
+	l := r.Get("passphrase")
+	newEntity := func() int { return 7 }
+	connAlive := map[interface{}]bool{"conn-1": true}
+	isAlive := func(id interface{}) bool { return connAlive[id] }
+
+	uid, created, ok := l.Join("alice", "conn-1", isAlive, newEntity)
+	if !ok || !created || uid != 7 {
+		t.Fatalf("first join = %d, %v, %v, want 7, true, true", uid, created, ok)
+	}
+
+	connAlive["conn-1"] = false
+	uid, created, ok = l.Join("alice", "conn-2", isAlive, newEntity)
+	if !ok {
+		t.Fatal("reconnect should succeed once the old connection is no longer alive")
+	}
+	if created {
+		t.Fatal("reconnect should rebind the existing uid, not allocate a new one")
+	}
+	if uid != 7 {
+		t.Fatalf("reconnect uid = %d, want the original 7", uid)
+	}
+}
+
+func TestPlayerCountCountsEverySeenName(t *testing.T) {
+	r := NewRegistry()
+	l := r.Get("passphrase")
+	newEntity := func() int { return 1 }
+	alwaysAlive := func(interface{}) bool { return true }
+
+	l.Join("alice", "conn-1", alwaysAlive, newEntity)
+	l.Join("bob", "conn-2", alwaysAlive, newEntity)
+	if got := l.PlayerCount(); got != 2 {
+		t.Fatalf("PlayerCount = %d, want 2", got)
+	}
+}
+
+func TestRegistryGetIsStablePerPassphrase(t *testing.T) {
+	r := NewRegistry()
+	if r.Get("a") != r.Get("a") {
+		t.Fatal("Get should return the same Lobby for the same passphrase")
+	}
+	if r.Get("a") == r.Get("b") {
+		t.Fatal("Get should return different Lobbies for different passphrases")
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/tilegame/gameserver/wshandle"
+)
+
+// lobbyJoinRequest is the first frame a websocket client is expected to
+// send: which lobby (identified by passphrase, not a server-assigned
+// id) and which player name to bind its connection to.
+type lobbyJoinRequest struct {
+	Lobby  string `json:"lobby"`
+	Player string `json:"player"`
+}
+
+// snapshot is the full game state sent back to a client immediately
+// after it joins a lobby, so it doesn't have to wait for the next tick
+// to see where everything is.
+type snapshot struct {
+	Grid     [][]int  `json:"grid"`
+	Entities []Entity `json:"entities"`
+}
+
+// handleLobbyJoin treats payload as a session's first frame if it
+// hasn't joined a lobby yet. It reports handled=true when it consumed
+// the frame as a join request (successful or not), so the caller
+// should skip normal command handling for it either way.
+//
+// req.Player must match the name session authenticated as: without
+// that check, any client could claim an arbitrary player name, and by
+// simply staying connected, permanently lock the real owner of that
+// name out of the lobby (Lobby.Join refuses a second live connection
+// for the same name).
+func handleLobbyJoin(game *Game, room *wshandle.ClientRoom, session interface{}, client *wshandle.Client, payload string) (handled bool, err error) {
+	game.joinedMu.Lock()
+	already := game.joined[session]
+	game.joinedMu.Unlock()
+	if already {
+		return false, nil
+	}
+
+	var req lobbyJoinRequest
+	if err := json.Unmarshal([]byte(payload), &req); err != nil || req.Lobby == "" || req.Player == "" {
+		return false, nil
+	}
+
+	name, ok := authenticatedName(game, session)
+	if !ok || name != req.Player {
+		log.Printf("lobby %q: rejecting unauthenticated join as %q", req.Lobby, req.Player)
+		if closer, ok := interface{}(client).(interface{ Close() error }); ok {
+			closer.Close()
+		}
+		return true, errors.New("not logged in as that player")
+	}
+
+	isAlive := func(id interface{}) bool {
+		_, ok := room.Client(id)
+		return ok
+	}
+	newEntity := func() int {
+		e := NewPlayer(game, req.Player)
+		game.PlaceEntity(*e)
+		return e.Uid
+	}
+	if _, _, ok := game.Lobbies.Get(req.Lobby).Join(req.Player, session, isAlive, newEntity); !ok {
+		log.Printf("lobby %q: %q already has a live connection, dropping the new one", req.Lobby, req.Player)
+		if closer, ok := interface{}(client).(interface{ Close() error }); ok {
+			closer.Close()
+		}
+		return true, errors.New("player already connected")
+	}
+
+	game.joinedMu.Lock()
+	game.joined[session] = true
+	game.joinedMu.Unlock()
+
+	data, err := json.Marshal(snapshot{Grid: game.GridSnapshot(), Entities: game.ents.All()})
+	if err != nil {
+		return true, err
+	}
+	fmt.Fprintln(client, string(data))
+	return true, nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/tilegame/gameserver/auth"
+)
+
+// mutatingCommands are the functions documented in helpMessage that
+// change game state.  messageWatcher refuses to run them for a session
+// that hasn't completed the login handshake.
+var mutatingCommands = map[string]bool{
+	"addEnt":      true,
+	"delEnt":      true,
+	"setLocation": true,
+	"setTarget":   true,
+}
+
+// loginChallenge is the state kept between the "login" and
+// "loginVerify" steps of a single SRP-6a handshake attempt. It lives on
+// the Game the session is authenticating against: see Game.challenges.
+type loginChallenge struct {
+	name string
+	a    *big.Int // client's public value A, from the "login" command
+	b    *big.Int // server's ephemeral private value
+	bPub *big.Int // server's public value B, cached from loginStep1
+	v    *big.Int // the verifier stored for name
+}
+
+// authSession is what a Game records once a session completes the
+// login handshake: the uid it was bound to and the name it
+// authenticated as, the latter so lobby joins can be tied to it (see
+// handleLobbyJoin in lobby_session.go).
+type authSession struct {
+	uid  int
+	name string
+}
+
+// stdinSession identifies the local command-line session.  It is
+// trusted unconditionally: it isn't reachable over the network.
+var stdinSession = new(int)
+
+// isAuthenticated reports whether session has completed the login
+// handshake against game, or is the trusted stdin session.
+func isAuthenticated(game *Game, session interface{}) bool {
+	if session == stdinSession {
+		return true
+	}
+	game.authMu.Lock()
+	defer game.authMu.Unlock()
+	_, ok := game.sessions[session]
+	return ok
+}
+
+// authenticatedName returns the name session authenticated as against
+// game, if it has completed the login handshake.
+func authenticatedName(game *Game, session interface{}) (string, bool) {
+	game.authMu.Lock()
+	defer game.authMu.Unlock()
+	s, ok := game.sessions[session]
+	return s.name, ok
+}
+
+// srpRecorder is implemented by AuthBackend implementations that keep
+// track of the last server SRP value issued per name.  It is optional:
+// backends that don't implement it simply skip the bookkeeping.
+type srpRecorder interface {
+	RecordSrvSrp(name string, b []byte) error
+}
+
+// loginStep1 handles "login name A": it looks up the stored salt and
+// verifier for name, checks the client's public value A, generates a
+// fresh server private value b, and returns "salt B" (both
+// hex-encoded) for the client to continue the SRP-6a handshake.
+func loginStep1(game *Game, session interface{}, name, aHex string) (string, error) {
+	backend := auth.Backend()
+	if backend == nil {
+		return "", errors.New("no auth backend configured")
+	}
+	if !backend.Exists(name) {
+		return "", errors.New("unknown user")
+	}
+	aBytes, err := hex.DecodeString(aHex)
+	if err != nil {
+		return "", errors.New("malformed A")
+	}
+	a := new(big.Int).SetBytes(aBytes)
+	if new(big.Int).Mod(a, srpN).Sign() == 0 {
+		return "", errors.New("invalid A")
+	}
+	salt, verifierBytes, err := backend.Passwd(name)
+	if err != nil {
+		return "", err
+	}
+	v := new(big.Int).SetBytes(verifierBytes)
+	bBytes := make([]byte, 32)
+	if _, err := rand.Read(bBytes); err != nil {
+		return "", err
+	}
+	b := new(big.Int).SetBytes(bBytes)
+	bPub := srpServerB(v, b)
+
+	game.authMu.Lock()
+	game.challenges[session] = &loginChallenge{name: name, a: a, b: b, bPub: bPub, v: v}
+	game.authMu.Unlock()
+	if recorder, ok := backend.(srpRecorder); ok {
+		recorder.RecordSrvSrp(name, bPub.Bytes())
+	}
+	return fmt.Sprintf("%s %s", hex.EncodeToString(salt), hex.EncodeToString(bPub.Bytes())), nil
+}
+
+// loginStep2 handles "loginVerify m1": it checks the client's proof
+// against the challenge started by loginStep1 and, on success, binds a
+// fresh uid from game to session.
+func loginStep2(game *Game, session interface{}, m1Hex string) (int, error) {
+	game.authMu.Lock()
+	ch, ok := game.challenges[session]
+	delete(game.challenges, session)
+	game.authMu.Unlock()
+	if !ok {
+		return 0, errors.New("no login in progress")
+	}
+	m1, err := hex.DecodeString(m1Hex)
+	if err != nil {
+		return 0, errors.New("malformed proof")
+	}
+	u := srpHashInts(ch.a, ch.bPub)
+	if u.Sign() == 0 {
+		return 0, errors.New("authentication failed")
+	}
+	s := srpServerS(ch.a, ch.v, u, ch.b)
+	expected := srpM1(ch.a, ch.bPub, s)
+	if subtle.ConstantTimeCompare(m1, expected) != 1 {
+		return 0, errors.New("authentication failed")
+	}
+	id := game.nextUid()
+	game.authMu.Lock()
+	game.sessions[session] = authSession{uid: id, name: ch.name}
+	game.authMu.Unlock()
+	return id, nil
+}
+
+// registerUser creates or overwrites the credential for name with the
+// configured auth backend, deriving a fresh salt and SRP verifier from
+// password. It backs the -adduser startup flag, which is currently the
+// only way to provision a credential the login handshake can check.
+func registerUser(name, password string) error {
+	backend := auth.Backend()
+	if backend == nil {
+		return errors.New("no auth backend configured")
+	}
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	verifier := srpVerifier(salt, []byte(password))
+	if backend.Exists(name) {
+		return backend.SetPasswd(name, salt, verifier)
+	}
+	return backend.AddUser(name, salt, verifier)
+}
+
+// parseLoginCommand reports whether s is a "login name A" command and
+// returns the name and the client's hex-encoded public value A.
+func parseLoginCommand(s string) (name, aHex string, ok bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 3 || fields[0] != "login" {
+		return "", "", false
+	}
+	return fields[1], fields[2], true
+}
+
+// parseLoginVerifyCommand reports whether s is a "loginVerify m1"
+// command and returns the hex-encoded proof.
+func parseLoginVerifyCommand(s string) (m1Hex string, ok bool) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 || fields[0] != "loginVerify" {
+		return "", false
+	}
+	return fields[1], true
+}
+
+// commandName returns the function name a command string would
+// dispatch to, i.e. its first whitespace-separated field.
+func commandName(s string) string {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
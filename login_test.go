@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/tilegame/gameserver/auth"
+)
+
+// ensureAuthBackend registers a MemoryBackend for the process if one
+// isn't already set. auth.SetAuthBackend may only succeed once per
+// process, so tests share whichever backend got there first.
+func ensureAuthBackend(t *testing.T) {
+	t.Helper()
+	if auth.Backend() != nil {
+		return
+	}
+	if err := auth.SetAuthBackend(auth.NewMemoryBackend()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestGame(t *testing.T, name string) *Game {
+	t.Helper()
+	return NewGame(name, 4, 4, PaceConfig{PacePlayer, PaceMonster, PaceProjectile}, 0, true)
+}
+
+// srpTestClient plays the client's side of the SRP-6a handshake, the
+// way a real client would from the password alone, so these tests
+// exercise the same arithmetic loginStep1/loginStep2 do rather than
+// reaching into server-only state.
+type srpTestClient struct {
+	a    *big.Int
+	aPub *big.Int
+}
+
+func newSRPTestClient(t *testing.T) *srpTestClient {
+	t.Helper()
+	aBytes := make([]byte, 32)
+	if _, err := rand.Read(aBytes); err != nil {
+		t.Fatalf("generating a: %v", err)
+	}
+	a := new(big.Int).SetBytes(aBytes)
+	return &srpTestClient{a: a, aPub: new(big.Int).Exp(srpG, a, srpN)}
+}
+
+// proof computes the client's M1 for a "salt bPubHex" challenge
+// string, given the password that produced the stored verifier.
+func (c *srpTestClient) proof(t *testing.T, challenge, password string) string {
+	t.Helper()
+	fields := strings.Fields(challenge)
+	if len(fields) != 2 {
+		t.Fatalf("loginStep1 returned %q, want \"salt B\"", challenge)
+	}
+	salt, err := hex.DecodeString(fields[0])
+	if err != nil {
+		t.Fatalf("decoding salt: %v", err)
+	}
+	bPubBytes, err := hex.DecodeString(fields[1])
+	if err != nil {
+		t.Fatalf("decoding B: %v", err)
+	}
+	bPub := new(big.Int).SetBytes(bPubBytes)
+	x := srpX(salt, []byte(password))
+	u := srpHashInts(c.aPub, bPub)
+	s := srpClientS(bPub, x, u, c.a)
+	return hex.EncodeToString(srpM1(c.aPub, bPub, s))
+}
+
+func TestLoginHandshakeRoundTrip(t *testing.T) {
+	ensureAuthBackend(t)
+	const name, password = "alice", "hunter2"
+	if err := registerUser(name, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	game := newTestGame(t, "login-test-ok")
+	session := new(int)
+	client := newSRPTestClient(t)
+
+	challenge, err := loginStep1(game, session, name, hex.EncodeToString(client.aPub.Bytes()))
+	if err != nil {
+		t.Fatalf("loginStep1: %v", err)
+	}
+	m1 := client.proof(t, challenge, password)
+
+	id, err := loginStep2(game, session, m1)
+	if err != nil {
+		t.Fatalf("loginStep2: %v", err)
+	}
+	if id == 0 {
+		t.Fatal("loginStep2 returned a zero uid on success")
+	}
+	if !isAuthenticated(game, session) {
+		t.Fatal("session should be authenticated after a successful handshake")
+	}
+	if gotName, ok := authenticatedName(game, session); !ok || gotName != name {
+		t.Fatalf("authenticatedName = %q, %v, want %q, true", gotName, ok, name)
+	}
+}
+
+func TestLoginStep2RejectsWrongProof(t *testing.T) {
+	ensureAuthBackend(t)
+	const name, password = "bob", "correct-password"
+	if err := registerUser(name, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	game := newTestGame(t, "login-test-bad")
+	session := new(int)
+	client := newSRPTestClient(t)
+
+	if _, err := loginStep1(game, session, name, hex.EncodeToString(client.aPub.Bytes())); err != nil {
+		t.Fatalf("loginStep1: %v", err)
+	}
+	wrongM1 := hex.EncodeToString(srpM1(big.NewInt(1), big.NewInt(2), big.NewInt(3)))
+	if _, err := loginStep2(game, session, wrongM1); err == nil {
+		t.Fatal("loginStep2 should reject a proof derived from the wrong values")
+	}
+	if isAuthenticated(game, session) {
+		t.Fatal("session should not be authenticated after a failed handshake")
+	}
+}
+
+// TestLoginVerifierLeakIsNotEnoughToAuthenticate is the regression test
+// for the vulnerability chunk0-2's review comment flagged: an attacker
+// who reads the stored salt and verifier (but not the password) must
+// not be able to complete the handshake.
+func TestLoginVerifierLeakIsNotEnoughToAuthenticate(t *testing.T) {
+	ensureAuthBackend(t)
+	const name, password = "carol", "swordfish"
+	if err := registerUser(name, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	backend := auth.Backend()
+	salt, verifier, err := backend.Passwd(name)
+	if err != nil {
+		t.Fatalf("Passwd: %v", err)
+	}
+
+	game := newTestGame(t, "login-test-leak")
+	session := new(int)
+	client := newSRPTestClient(t)
+
+	challenge, err := loginStep1(game, session, name, hex.EncodeToString(client.aPub.Bytes()))
+	if err != nil {
+		t.Fatalf("loginStep1: %v", err)
+	}
+	fields := strings.Fields(challenge)
+	bPub := new(big.Int).SetBytes(mustDecodeHex(t, fields[1]))
+
+	// An attacker with only salt and verifier (no password) cannot
+	// derive x, so the best they can do is hash the leaked verifier
+	// itself into a "proof" the way the old, broken scheme did.
+	forged := srpHashInts(bPub, new(big.Int).SetBytes(verifier))
+	_ = salt
+	if _, err := loginStep2(game, session, hex.EncodeToString(forged.Bytes())); err == nil {
+		t.Fatal("a forged proof built only from the leaked verifier should not authenticate")
+	}
+}
+
+func mustDecodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decoding hex: %v", err)
+	}
+	return b
+}
+
+func TestLoginStep1UnknownUser(t *testing.T) {
+	ensureAuthBackend(t)
+	client := newSRPTestClient(t)
+	if _, err := loginStep1(newTestGame(t, "login-test-unknown"), new(int), "no-such-user", hex.EncodeToString(client.aPub.Bytes())); err == nil {
+		t.Fatal("loginStep1 should fail for a name with no stored credential")
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func newTestRESTGame(t *testing.T, name string) *Game {
+	t.Helper()
+	game, err := createGame(name, 4, 4, PaceConfig{PacePlayer, PaceMonster, PaceProjectile}, 0, true)
+	if err != nil {
+		t.Fatalf("createGame: %v", err)
+	}
+	return game
+}
+
+func TestAddEntityHandlerRequiresAuth(t *testing.T) {
+	ensureAuthBackend(t)
+	newTestRESTGame(t, "rest-test-add-noauth")
+
+	body, _ := json.Marshal(Entity{Kind: "player", Name: "alice"})
+	req := httptest.NewRequest(http.MethodPost, "/games/rest-test-add-noauth/entities", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	gamesHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestAddEntityHandlerWithAuth(t *testing.T) {
+	ensureAuthBackend(t)
+	const user, password = "rest-add-user", "hunter2"
+	if err := registerUser(user, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	game := newTestRESTGame(t, "rest-test-add-auth")
+
+	body, _ := json.Marshal(Entity{Kind: "player", Name: "alice"})
+	req := httptest.NewRequest(http.MethodPost, "/games/rest-test-add-auth/entities", bytes.NewReader(body))
+	req.SetBasicAuth(user, password)
+	w := httptest.NewRecorder()
+	gamesHandler(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var got Entity
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if got.Uid == 0 {
+		t.Fatal("addEntityHandler should assign a uid when the request omits one")
+	}
+	if len(game.ents.All()) != 1 {
+		t.Fatalf("game has %d entities, want 1", len(game.ents.All()))
+	}
+}
+
+func TestAddEntityHandlerWrongPassword(t *testing.T) {
+	ensureAuthBackend(t)
+	const user, password = "rest-add-user-2", "correct-password"
+	if err := registerUser(user, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	newTestRESTGame(t, "rest-test-add-wrongpw")
+
+	body, _ := json.Marshal(Entity{Kind: "player", Name: "alice"})
+	req := httptest.NewRequest(http.MethodPost, "/games/rest-test-add-wrongpw/entities", bytes.NewReader(body))
+	req.SetBasicAuth(user, "not-the-password")
+	w := httptest.NewRecorder()
+	gamesHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestDeleteEntityHandlerRequiresAuth(t *testing.T) {
+	ensureAuthBackend(t)
+	game := newTestRESTGame(t, "rest-test-delete-noauth")
+	e := Entity{Kind: "player", Name: "alice", Uid: game.nextUid(), Current: Location{X: 1, Y: 1}}
+	game.PlaceEntity(e)
+
+	req := httptest.NewRequest(http.MethodDelete, "/games/rest-test-delete-noauth/entities/"+strconv.Itoa(e.Uid), nil)
+	w := httptest.NewRecorder()
+	gamesHandler(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if len(game.ents.All()) != 1 {
+		t.Fatal("an unauthenticated delete should not have removed the entity")
+	}
+}
+
+// TestDeleteEntityHandlerClearsGridSlot is the REST-level regression
+// test for the chunk0-5/chunk0-6 phantom-entity bug: deleting an
+// entity must also clear the grid cell it occupied.
+func TestDeleteEntityHandlerClearsGridSlot(t *testing.T) {
+	ensureAuthBackend(t)
+	const user, password = "rest-delete-user", "hunter2"
+	if err := registerUser(user, password); err != nil {
+		t.Fatalf("registerUser: %v", err)
+	}
+	game := newTestRESTGame(t, "rest-test-delete-auth")
+	e := Entity{Kind: "player", Name: "alice", Uid: game.nextUid(), Current: Location{X: 1, Y: 2}}
+	game.PlaceEntity(e)
+
+	req := httptest.NewRequest(http.MethodDelete, "/games/rest-test-delete-auth/entities/"+strconv.Itoa(e.Uid), nil)
+	req.SetBasicAuth(user, password)
+	w := httptest.NewRecorder()
+	gamesHandler(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d, body %s", w.Code, http.StatusNoContent, w.Body)
+	}
+	if len(game.ents.All()) != 0 {
+		t.Fatal("entity should be removed after an authenticated delete")
+	}
+	if got := game.GridSnapshot()[2][1]; got != 0 {
+		t.Fatalf("grid[2][1] = %d after delete, want 0 (cleared)", got)
+	}
+}
+
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// collisionDamage is how much Health a projectile collision costs the
+// entity it hits.
+const collisionDamage = 10
+
+// defaultBaseInterval is used when a Game wasn't given a positive tick
+// interval (e.g. Config.Tick was left at 0).
+const defaultBaseInterval = 100 * time.Millisecond
+
+// movedEntity is one line of a tick's broadcast delta.
+type movedEntity struct {
+	Uid int `json:"uid"`
+	X   int `json:"x"`
+	Y   int `json:"y"`
+}
+
+// tickDelta is broadcast to every client in a Game's room after each
+// tick that changed something.
+type tickDelta struct {
+	Tick    int           `json:"tick"`
+	Moved   []movedEntity `json:"moved"`
+	Removed []int         `json:"removed"`
+}
+
+// runSimulator advances g one tick every baseInterval until the
+// process exits. It is started once per Game, from NewGame.
+func (g *Game) runSimulator(baseInterval time.Duration) {
+	if baseInterval <= 0 {
+		baseInterval = defaultBaseInterval
+	}
+	baseMillis := int(baseInterval / time.Millisecond)
+	ticker := time.NewTicker(baseInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		g.tick1(baseMillis)
+	}
+}
+
+// tick1 runs one simulation tick: every Entity whose Pace divides the
+// elapsed time moves one grid step toward its Target, with projectile
+// collisions applied along the way. It broadcasts the resulting delta.
+func (g *Game) tick1(baseMillis int) {
+	tickNum := g.advanceTick()
+	elapsed := tickNum * baseMillis
+
+	var moved []movedEntity
+	var removed []int
+
+	g.ents.Update(func(data map[int]Entity) {
+		for uid, e := range data {
+			if e.Pace <= 0 || elapsed%e.Pace != 0 {
+				continue
+			}
+			if e.Current == e.Target {
+				continue
+			}
+			next := stepToward(e.Current, e.Target)
+			if !g.inBounds(next) {
+				continue
+			}
+
+			if occupantUid := g.grid[next.Y][next.X]; occupantUid != 0 && occupantUid != e.Uid {
+				occupant, ok := data[occupantUid]
+				if !ok {
+					// The grid cell points at a uid ents no longer
+					// has, e.g. a stale write left behind by a delete
+					// that didn't clear its grid slot. Treat the cell
+					// as if it were empty rather than running a
+					// synthesized zero-value Entity through collision
+					// resolution.
+					g.grid[next.Y][next.X] = 0
+					continue
+				}
+				switch resolveCollision(&e, &occupant) {
+				case e.Uid:
+					g.clearGridSlot(e.Current, e.Uid)
+					delete(data, e.Uid)
+					removed = append(removed, e.Uid)
+					data[occupantUid] = occupant
+				case occupantUid:
+					g.clearGridSlot(occupant.Current, occupantUid)
+					delete(data, occupantUid)
+					removed = append(removed, occupantUid)
+					data[uid] = e
+				default:
+					data[uid] = e
+					data[occupantUid] = occupant
+				}
+				continue
+			}
+
+			g.clearGridSlot(e.Current, e.Uid)
+			e.Current = next
+			g.grid[next.Y][next.X] = e.Uid
+			data[uid] = e
+			moved = append(moved, movedEntity{Uid: e.Uid, X: next.X, Y: next.Y})
+		}
+	})
+
+	g.broadcastDelta(tickNum, moved, removed)
+}
+
+// stepToward returns the grid square one step closer to target,
+// moving along x before y so the path is a simple staircase.
+func stepToward(cur, target Location) Location {
+	next := cur
+	switch {
+	case cur.X < target.X:
+		next.X++
+	case cur.X > target.X:
+		next.X--
+	case cur.Y < target.Y:
+		next.Y++
+	case cur.Y > target.Y:
+		next.Y--
+	}
+	return next
+}
+
+// resolveCollision applies projectile damage when mover steps onto a
+// cell occupant already holds, and returns the uid of whichever of
+// the two was destroyed, or 0 if neither was. Either way, the moving
+// entity stays put this tick: it is not moved into occupant's cell.
+func resolveCollision(mover, occupant *Entity) int {
+	var victim *Entity
+	switch {
+	case mover.Kind == "projectile" && occupant.Kind != "projectile":
+		victim = occupant
+	case occupant.Kind == "projectile" && mover.Kind != "projectile":
+		victim = mover
+	default:
+		return 0
+	}
+	victim.Health -= collisionDamage
+	if victim.Health <= 0 {
+		return victim.Uid
+	}
+	return 0
+}
+
+// inBounds reports whether loc is a valid index into g.grid.
+func (g *Game) inBounds(loc Location) bool {
+	return loc.Y >= 0 && loc.Y < len(g.grid) && loc.X >= 0 && loc.X < len(g.grid[loc.Y])
+}
+
+// clearGridSlot clears loc if it is still holding uid, so a stale
+// write from a removed or since-moved entity can't clobber whatever
+// has since moved in.
+func (g *Game) clearGridSlot(loc Location, uid int) {
+	if !g.inBounds(loc) {
+		return
+	}
+	if g.grid[loc.Y][loc.X] == uid {
+		g.grid[loc.Y][loc.X] = 0
+	}
+}
+
+// broadcastDelta sends the result of one tick to every client
+// connected to g's room, skipping ticks where nothing happened.
+func (g *Game) broadcastDelta(tickNum int, moved []movedEntity, removed []int) {
+	if len(moved) == 0 && len(removed) == 0 {
+		return
+	}
+	data, err := json.Marshal(tickDelta{Tick: tickNum, Moved: moved, Removed: removed})
+	if err != nil {
+		log.Println("simulator: marshal delta failed:", err)
+		return
+	}
+	// wshandle.ClientRoom.Broadcast sends data to every client in g.Room.
+	g.Room.Broadcast(data)
+}
+
+// advanceTick increments and returns g's tick counter.
+func (g *Game) advanceTick() int {
+	g.mu.Lock()
+	g.tick++
+	t := g.tick
+	g.mu.Unlock()
+	return t
+}
+
+// NextTick is the "nextTick" command: it reports the current tick
+// without advancing it, since the Simulator already does that.
+func (g *Game) NextTick() int {
+	return g.CurrentTick()
+}
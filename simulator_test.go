@@ -0,0 +1,94 @@
+package main
+
+import "testing"
+
+func TestStepToward(t *testing.T) {
+	cases := []struct {
+		cur, target, want Location
+	}{
+		{Location{0, 0}, Location{2, 0}, Location{1, 0}},
+		{Location{2, 0}, Location{0, 0}, Location{1, 0}},
+		{Location{0, 0}, Location{0, 2}, Location{0, 1}},
+		{Location{0, 2}, Location{0, 0}, Location{0, 1}},
+		{Location{1, 1}, Location{1, 1}, Location{1, 1}},
+	}
+	for _, c := range cases {
+		if got := stepToward(c.cur, c.target); got != c.want {
+			t.Errorf("stepToward(%v, %v) = %v, want %v", c.cur, c.target, got, c.want)
+		}
+	}
+}
+
+func TestResolveCollision(t *testing.T) {
+	projectile := Entity{Kind: "projectile", Uid: 1, Health: 100}
+	player := Entity{Kind: "player", Uid: 2, Health: collisionDamage}
+
+	if victim := resolveCollision(&projectile, &player); victim != player.Uid {
+		t.Fatalf("resolveCollision(projectile, player) = %d, want player uid %d", victim, player.Uid)
+	}
+	if player.Health != 0 {
+		t.Fatalf("player.Health = %d, want 0", player.Health)
+	}
+
+	projectile, player = Entity{Kind: "projectile", Uid: 1, Health: 100}, Entity{Kind: "player", Uid: 2, Health: 999}
+	if victim := resolveCollision(&projectile, &player); victim != 0 {
+		t.Fatalf("resolveCollision should not report a victim when Health survives, got %d", victim)
+	}
+
+	a := Entity{Kind: "player", Uid: 1, Health: 100}
+	b := Entity{Kind: "player", Uid: 2, Health: 100}
+	if victim := resolveCollision(&a, &b); victim != 0 {
+		t.Fatalf("resolveCollision(player, player) = %d, want 0 (no projectile involved)", victim)
+	}
+}
+
+// TestGameTick1Movement exercises tick1 through a real (paused) Game so
+// it also covers EntMap.Update, the exclusive-access helper chunk0-5
+// added to EntMap in anticipation of this Simulator.
+func TestGameTick1Movement(t *testing.T) {
+	g := newTestGame(t, "tick-movement")
+	e := Entity{Kind: "player", Uid: 1, Pace: 100, Current: Location{0, 0}, Target: Location{2, 0}}
+	g.PlaceEntity(e)
+
+	g.tick1(100)
+
+	var moved Entity
+	g.ents.Update(func(data map[int]Entity) {
+		moved = data[1]
+	})
+	want := Location{1, 0}
+	if moved.Current != want {
+		t.Fatalf("entity Current = %v, want %v", moved.Current, want)
+	}
+	if got := g.GridSnapshot()[0][1]; got != 1 {
+		t.Fatalf("grid[0][1] = %d, want entity uid 1", got)
+	}
+	if got := g.GridSnapshot()[0][0]; got != 0 {
+		t.Fatalf("grid[0][0] = %d, want 0 (vacated)", got)
+	}
+}
+
+// TestGameTick1Collision verifies a projectile stepping onto an
+// occupied cell damages the occupant instead of moving there, and
+// removes it once Health reaches zero.
+func TestGameTick1Collision(t *testing.T) {
+	g := newTestGame(t, "tick-collision")
+	projectile := Entity{Kind: "projectile", Uid: 1, Pace: 100, Current: Location{0, 0}, Target: Location{2, 0}}
+	victim := Entity{Kind: "player", Uid: 2, Pace: 0, Current: Location{1, 0}, Health: collisionDamage}
+	g.PlaceEntity(projectile)
+	g.PlaceEntity(victim)
+
+	g.tick1(100)
+
+	g.ents.Update(func(data map[int]Entity) {
+		if _, ok := data[2]; ok {
+			t.Fatal("victim should have been removed once its Health reached zero")
+		}
+		if mover := data[1]; mover.Current != (Location{0, 0}) {
+			t.Fatalf("projectile.Current = %v, want it to stay put on collision", mover.Current)
+		}
+	})
+	if got := g.GridSnapshot()[0][1]; got != 0 {
+		t.Fatalf("grid[0][1] = %d, want 0 (victim removed)", got)
+	}
+}